@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// VectorObject is a single embedded document ready to be persisted in a
+// VectorStore.
+type VectorObject struct {
+	Vector     []float32
+	Properties map[string]any
+}
+
+// RetrievedDoc is a single result returned from a VectorStore query,
+// carrying enough provenance to attribute an answer back to its source
+// chunk.
+type RetrievedDoc struct {
+	DocID      string
+	Source     string
+	Title      string
+	ChunkIndex int
+	Text       string
+	Score      float64
+}
+
+// key returns a stable identity for a chunk, used to dedupe and fuse
+// rankings across retrieval methods.
+func (d RetrievedDoc) key() string {
+	return d.DocID + "#" + strconv.Itoa(d.ChunkIndex)
+}
+
+// VectorStore abstracts the backing store used to persist and query
+// embedded documents, so the HTTP handlers don't depend on Weaviate
+// directly.
+type VectorStore interface {
+	Upsert(ctx context.Context, objs []VectorObject) error
+	Query(ctx context.Context, vector []float32, limit int) ([]RetrievedDoc, error)
+	QueryBM25(ctx context.Context, query string, limit int) ([]RetrievedDoc, error)
+}
+
+// Embedder turns text into vectors.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	BatchEmbed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// LLM generates a completion for a prompt, optionally streaming it token by
+// token.
+type LLM interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+	GenerateStream(ctx context.Context, prompt string) (<-chan string, <-chan error)
+}
+
+// newVectorStore builds the VectorStore selected by the VECTOR_BACKEND env
+// var, defaulting to "weaviate". Weaviate runs fine as a local container, so
+// it remains the only store even for a fully local (Ollama) setup.
+func newVectorStore(backend string) (VectorStore, error) {
+	switch backend {
+	case "", "weaviate":
+		return newWeaviateStore()
+	default:
+		return nil, fmt.Errorf("unknown VECTOR_BACKEND %q", backend)
+	}
+}
+
+// newEmbedder builds the Embedder selected by the EMBED_BACKEND env var,
+// defaulting to "gemini".
+func newEmbedder(ctx context.Context, backend string, genClient *genai.Client) (Embedder, error) {
+	switch backend {
+	case "", "gemini":
+		return newGeminiEmbedder(genClient), nil
+	case "ollama":
+		return newOllamaClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBED_BACKEND %q", backend)
+	}
+}
+
+// newLLM builds the LLM selected by the LLM_BACKEND env var, defaulting to
+// "gemini".
+func newLLM(ctx context.Context, backend string, genClient *genai.Client) (LLM, error) {
+	switch backend {
+	case "", "gemini":
+		return newGeminiLLM(genClient), nil
+	case "ollama":
+		return newOllamaClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/google/uuid"
+)
+
+var (
+	chatSessionTTL   = durationEnvOrDefault("CHAT_SESSION_TTL", 30*time.Minute)
+	chatMaxTurns     = intEnvOrDefault("CHAT_MAX_HISTORY_TURNS", 10)
+	condenseTemplate = `Given the conversation so far and a follow-up message, rewrite the follow-up as a standalone question that includes any context it implicitly refers to. If the follow-up is already standalone, return it unchanged.
+
+### Conversation so far:
+%s
+
+### Follow-up message:
+%s
+
+### Standalone question:`
+)
+
+func durationEnvOrDefault(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// ChatSession wraps a genai chat so a caller can carry multi-turn context
+// across requests by session ID. mu serializes turns on this session: two
+// requests racing on the same session_id would otherwise read/append
+// chat.History concurrently.
+type ChatSession struct {
+	mu         sync.Mutex
+	chat       *genai.ChatSession
+	lastActive time.Time
+}
+
+// Lock and Unlock let callers hold the session for the full duration of a
+// turn (condense -> retrieve -> send -> trim), not just a single field
+// access.
+func (s *ChatSession) Lock()   { s.mu.Lock() }
+func (s *ChatSession) Unlock() { s.mu.Unlock() }
+
+// ChatStore keeps ChatSessions in memory, keyed by session ID, and expires
+// them after ttl of inactivity. It's deliberately a plain map today; a
+// Redis-backed implementation can satisfy the same shape if sessions need
+// to survive a restart or be shared across replicas.
+type ChatStore struct {
+	mu       sync.Mutex
+	sessions map[string]*ChatSession
+	ttl      time.Duration
+}
+
+func newChatStore(ttl time.Duration) *ChatStore {
+	return &ChatStore{
+		sessions: make(map[string]*ChatSession),
+		ttl:      ttl,
+	}
+}
+
+// getOrCreate returns the session for id, creating a new one (with a fresh
+// ID if id is empty) if it doesn't exist or has expired. It also sweeps any
+// other expired sessions while it holds the lock.
+func (s *ChatStore) getOrCreate(id string, model *genai.GenerativeModel) (string, *ChatSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for sid, sess := range s.sessions {
+		if now.Sub(sess.lastActive) > s.ttl {
+			delete(s.sessions, sid)
+		}
+	}
+
+	if sess, ok := s.sessions[id]; ok && id != "" {
+		sess.lastActive = now
+		return id, sess
+	}
+
+	if id == "" {
+		id = uuid.NewString()
+	}
+	sess := &ChatSession{chat: model.StartChat(), lastActive: now}
+	s.sessions[id] = sess
+	return id, sess
+}
+
+// condenseQuestion rewrites message into a standalone query using the chat
+// history so retrieval still works for follow-ups like "what about its
+// performance?". With no history yet, the message is used as-is.
+func condenseQuestion(ctx context.Context, llm LLM, chat *genai.ChatSession, message string) (string, error) {
+	if len(chat.History) == 0 {
+		return message, nil
+	}
+	prompt := fmt.Sprintf(condenseTemplate, formatHistory(chat.History), message)
+	rewritten, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	rewritten = strings.TrimSpace(rewritten)
+	if rewritten == "" {
+		return message, nil
+	}
+	return rewritten, nil
+}
+
+// formatHistory renders a genai chat history as a plain "Role: text"
+// transcript for use inside a prompt.
+func formatHistory(history []*genai.Content) string {
+	var lines []string
+	for _, turn := range history {
+		var text strings.Builder
+		for _, part := range turn.Parts {
+			if pt, ok := part.(genai.Text); ok {
+				text.WriteString(string(pt))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", turn.Role, text.String()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceLastUserTurn overwrites the most recently appended user turn in
+// chat's history with message. SendMessage persists whatever parts it was
+// given as that turn; callers send the RAG-augmented prompt so the model
+// sees retrieved context, but the raw user message is what should survive
+// in history and feed the next turn's condense step.
+func replaceLastUserTurn(chat *genai.ChatSession, message string) {
+	n := len(chat.History)
+	if n < 2 {
+		return
+	}
+	chat.History[n-2] = &genai.Content{
+		Role:  "user",
+		Parts: []genai.Part{genai.Text(message)},
+	}
+}
+
+// trimHistory keeps at most maxTurns user/model exchanges, dropping the
+// oldest ones so the session stays under the model's context window.
+func trimHistory(chat *genai.ChatSession, maxTurns int) {
+	maxMessages := maxTurns * 2
+	if maxMessages <= 0 || len(chat.History) <= maxMessages {
+		return
+	}
+	chat.History = chat.History[len(chat.History)-maxMessages:]
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	defaultChunkSize    = 512
+	defaultChunkOverlap = 64
+)
+
+var (
+	chunkSize    = intEnvOrDefault("CHUNK_SIZE", defaultChunkSize)
+	chunkOverlap = intEnvOrDefault("CHUNK_OVERLAP", defaultChunkOverlap)
+)
+
+func intEnvOrDefault(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// chunkText splits text into overlapping windows of size runes, advancing
+// size-overlap runes at a time. It's a simple character-based splitter
+// rather than a token-aware one, which keeps the server dependency-free.
+func chunkText(text string, size, overlap int) []string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	step := size - overlap
+
+	var chunks []string
+	for start := 0; start < len(runes); start += step {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, string(runes[start:end]))
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
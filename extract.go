@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/jaytaylor/html2text"
+	"github.com/ledongthuc/pdf"
+)
+
+// extractText turns an ingestSource into plain text. Uploaded files
+// dispatch on their filename extension (PDF, HTML, Markdown/TXT pass
+// through as-is). URL sources are fetched first and dispatch on the
+// response's Content-Type instead, since a URL's path rarely carries a
+// file extension (e.g. "https://site/article").
+func extractText(src ingestSource) (string, error) {
+	if src.url != "" {
+		data, contentType, err := fetchURL(src.url)
+		if err != nil {
+			return "", err
+		}
+		return extractByContentType(data, contentType)
+	}
+
+	switch strings.ToLower(filepath.Ext(src.name)) {
+	case ".pdf":
+		return extractPDF(src.data)
+	case ".html", ".htm":
+		return html2text.FromString(string(src.data), html2text.Options{})
+	default:
+		return string(src.data), nil
+	}
+}
+
+// extractByContentType dispatches a fetched URL's body on its
+// Content-Type, defaulting to HTML since that's what a bare URL fetch
+// overwhelmingly returns.
+func extractByContentType(data []byte, contentType string) (string, error) {
+	switch {
+	case strings.Contains(contentType, "pdf"):
+		return extractPDF(data)
+	case strings.Contains(contentType, "plain"):
+		return string(data), nil
+	default:
+		return html2text.FromString(string(data), html2text.Options{})
+	}
+}
+
+func fetchURL(url string) ([]byte, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetch %s: unexpected status %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// extractPDF concatenates the plain text of every page in a PDF document.
+func extractPDF(data []byte) (string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(text)
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
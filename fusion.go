@@ -0,0 +1,40 @@
+package main
+
+import "sort"
+
+// reciprocalRankFusion merges a dense (vector) and a sparse (BM25) ranked
+// result list into a single ranked list using Reciprocal Rank Fusion:
+//
+//	score(d) = alpha / (k + rank_dense(d)) + (1-alpha) / (k + rank_sparse(d))
+//
+// Documents missing from one of the two lists simply don't receive a
+// contribution from that ranker. The fused score replaces each returned
+// doc's Score field. Results are sorted by descending fused score and
+// truncated to limit.
+func reciprocalRankFusion(dense, sparse []RetrievedDoc, alpha float64, k, limit int) []RetrievedDoc {
+	scores := make(map[string]float64)
+	docs := make(map[string]RetrievedDoc)
+
+	for rank, doc := range dense {
+		scores[doc.key()] += alpha / float64(k+rank+1)
+		docs[doc.key()] = doc
+	}
+	for rank, doc := range sparse {
+		scores[doc.key()] += (1 - alpha) / float64(k+rank+1)
+		docs[doc.key()] = doc
+	}
+
+	fused := make([]RetrievedDoc, 0, len(docs))
+	for key, doc := range docs {
+		doc.Score = scores[key]
+		fused = append(fused, doc)
+	}
+	sort.Slice(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+
+	if limit > 0 && len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused
+}
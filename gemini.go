@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// geminiEmbedder adapts genai's EmbeddingModel to the Embedder interface.
+type geminiEmbedder struct {
+	model *genai.EmbeddingModel
+}
+
+func newGeminiEmbedder(client *genai.Client) *geminiEmbedder {
+	return &geminiEmbedder{model: client.EmbeddingModel(embeddingModelName)}
+}
+
+func (e *geminiEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := e.model.EmbedContent(ctx, genai.Text(text))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Embedding.Values, nil
+}
+
+func (e *geminiEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	batch := e.model.NewBatch()
+	for _, t := range texts {
+		batch.AddContent(genai.Text(t))
+	}
+	resp, err := e.model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
+	}
+	out := make([][]float32, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		out[i] = e.Values
+	}
+	return out, nil
+}
+
+// geminiLLM adapts genai's GenerativeModel to the LLM interface.
+type geminiLLM struct {
+	model *genai.GenerativeModel
+}
+
+func newGeminiLLM(client *genai.Client) *geminiLLM {
+	return &geminiLLM{model: client.GenerativeModel(llmModel)}
+}
+
+func (g *geminiLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", err
+	}
+	return joinTextParts(resp)
+}
+
+func (g *geminiLLM) GenerateStream(ctx context.Context, prompt string) (<-chan string, <-chan error) {
+	tokens := make(chan string)
+	errs := make(chan error, 1)
+	iter := g.model.GenerateContentStream(ctx, genai.Text(prompt))
+	go func() {
+		defer close(tokens)
+		defer close(errs)
+		for {
+			chunk, err := iter.Next()
+			if err == iterator.Done {
+				return
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(chunk.Candidates) == 0 || chunk.Candidates[0].Content == nil {
+				continue
+			}
+			for _, part := range chunk.Candidates[0].Content.Parts {
+				if pt, ok := part.(genai.Text); ok {
+					tokens <- string(pt)
+				} else {
+					log.Printf("bad type of part: %v", pt)
+				}
+			}
+		}
+	}()
+	return tokens, errs
+}
+
+// joinTextParts concatenates the genai.Text parts of a generation response,
+// erroring out on any other content type the model might return.
+func joinTextParts(resp *genai.GenerateContentResponse) (string, error) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", fmt.Errorf("generation returned no candidates (blocked by safety or recitation filter?)")
+	}
+	var out []string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		pt, ok := part.(genai.Text)
+		if !ok {
+			return "", fmt.Errorf("unexpected content part type %T", part)
+		}
+		out = append(out, string(pt))
+	}
+	return strings.Join(out, "\n"), nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// geminiMaxBatchSize is the largest number of texts BatchEmbedContents will
+// accept in a single call; larger inputs are split into sub-batches.
+const geminiMaxBatchSize = 100
+
+type IngestJobStatus string
+
+const (
+	IngestQueued  IngestJobStatus = "queued"
+	IngestRunning IngestJobStatus = "running"
+	IngestDone    IngestJobStatus = "done"
+	IngestFailed  IngestJobStatus = "failed"
+)
+
+// IngestJob tracks the progress of one /ingest request across all of its
+// documents, which are processed concurrently by the worker pool.
+type IngestJob struct {
+	mu        sync.Mutex
+	ID        string          `json:"id"`
+	Status    IngestJobStatus `json:"status"`
+	Total     int             `json:"total"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Errors    []string        `json:"errors,omitempty"`
+}
+
+func (j *IngestJob) markStarted() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.Status == IngestQueued {
+		j.Status = IngestRunning
+	}
+}
+
+func (j *IngestJob) recordResult(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.Failed++
+		j.Errors = append(j.Errors, err.Error())
+	} else {
+		j.Succeeded++
+	}
+	if j.Succeeded+j.Failed == j.Total {
+		if j.Succeeded == 0 && j.Failed > 0 {
+			j.Status = IngestFailed
+		} else {
+			j.Status = IngestDone
+		}
+	}
+}
+
+func (j *IngestJob) snapshot() IngestJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return IngestJob{
+		ID:        j.ID,
+		Status:    j.Status,
+		Total:     j.Total,
+		Succeeded: j.Succeeded,
+		Failed:    j.Failed,
+		Errors:    append([]string(nil), j.Errors...),
+	}
+}
+
+// IngestStore keeps ingestion jobs in memory for the lifetime of the
+// process, keyed by job ID.
+type IngestStore struct {
+	mu   sync.Mutex
+	jobs map[string]*IngestJob
+}
+
+func newIngestStore() *IngestStore {
+	return &IngestStore{jobs: make(map[string]*IngestJob)}
+}
+
+func (s *IngestStore) create(total int) *IngestJob {
+	job := &IngestJob{ID: uuid.NewString(), Status: IngestQueued, Total: total}
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	return job
+}
+
+func (s *IngestStore) get(id string) (*IngestJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+// ingestWorkerCount is configurable via INGEST_WORKERS so deployments can
+// tune concurrency against their embedding rate limits.
+var ingestWorkerCount = intEnvOrDefault("INGEST_WORKERS", 4)
+
+// IngestPool is a bounded worker pool: submitted tasks queue up on a
+// buffered channel and run on a fixed number of goroutines, so a large
+// corpus can't spin up unbounded concurrent embedding calls.
+type IngestPool struct {
+	tasks chan func()
+}
+
+func newIngestPool(workers int) *IngestPool {
+	p := &IngestPool{tasks: make(chan func(), 256)}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+func (p *IngestPool) submit(task func()) {
+	p.tasks <- task
+}
+
+// ingestSource is one document to ingest, either an uploaded file or a URL
+// to fetch.
+type ingestSource struct {
+	name string
+	data []byte
+	url  string
+}
+
+// sourcesFromMultipart converts uploaded files into ingestSources.
+func sourcesFromMultipart(files []*multipart.FileHeader) ([]ingestSource, error) {
+	sources := make([]ingestSource, 0, len(files))
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, ingestSource{name: fh.Filename, data: data})
+	}
+	return sources, nil
+}
+
+// sourcesFromURLs converts a list of URLs into ingestSources; fetching
+// happens later, on the worker, so a bad URL only fails its own document.
+func sourcesFromURLs(urls []string) []ingestSource {
+	sources := make([]ingestSource, len(urls))
+	for i, u := range urls {
+		sources[i] = ingestSource{name: u, url: u}
+	}
+	return sources
+}
+
+// processSource extracts, chunks, embeds (in sub-batches capped at
+// geminiMaxBatchSize), and upserts a single ingest source.
+func processSource(ctx context.Context, src ingestSource, embedder Embedder, store VectorStore) error {
+	text, err := extractText(src)
+	if err != nil {
+		return err
+	}
+	chunks := chunkText(text, chunkSize, chunkOverlap)
+	docID := uuid.NewString()
+
+	for start := 0; start < len(chunks); start += geminiMaxBatchSize {
+		end := start + geminiMaxBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		sub := chunks[start:end]
+
+		embeddings, err := embedder.BatchEmbed(ctx, sub)
+		if err != nil {
+			return err
+		}
+		objs := make([]VectorObject, len(sub))
+		for i, chunk := range sub {
+			objs[i] = VectorObject{
+				Vector: embeddings[i],
+				Properties: map[string]any{
+					"text":        chunk,
+					"source":      src.name,
+					"doc_id":      docID,
+					"chunk_index": start + i,
+				},
+			}
+		}
+		if err := store.Upsert(ctx, objs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
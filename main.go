@@ -2,18 +2,18 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"github.com/gin-gonic/gin"
-	"github.com/google/generative-ai-go/genai"
-	"github.com/weaviate/weaviate-go-client/v4/weaviate"
-	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
-	"github.com/weaviate/weaviate/entities/models"
-	"google.golang.org/api/option"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/google/uuid"
+	"google.golang.org/api/option"
 )
 
 var (
@@ -21,6 +21,9 @@ var (
 	llmModel           = os.Getenv("LLM_MODEL")
 	embeddingModelName = os.Getenv("EMBEDDING_MODEL_NAME")
 	collectionClass    = os.Getenv("COLLECTION_CLASS")
+	vectorBackend      = os.Getenv("VECTOR_BACKEND")
+	llmBackend         = os.Getenv("LLM_BACKEND")
+	embedBackend       = os.Getenv("EMBED_BACKEND")
 	template           = `
 ### Question:
 %s
@@ -35,39 +38,173 @@ var (
 - If the question does not relate to the context, answer it as normal.`
 )
 
+type DocumentInput struct {
+	ID       string         `json:"id"`
+	Source   string         `json:"source"`
+	Title    string         `json:"title"`
+	Text     string         `json:"text"`
+	Metadata map[string]any `json:"metadata"`
+}
+
 type AddDocumentsRequest struct {
-	Documents []string `json:"documents"`
+	Documents []DocumentInput `json:"documents"`
 }
 
 type AskQuestionRequest struct {
 	Question string `json:"question"`
 }
 
+// SourceAttribution identifies the chunk an answer was grounded on, so UIs
+// can render citations.
+type SourceAttribution struct {
+	DocID      string  `json:"doc_id"`
+	Source     string  `json:"source"`
+	Title      string  `json:"title"`
+	ChunkIndex int     `json:"chunk_index"`
+	Score      float64 `json:"score"`
+	Snippet    string  `json:"snippet"`
+}
+
+type AskResponse struct {
+	Answer  string              `json:"answer"`
+	Sources []SourceAttribution `json:"sources"`
+}
+
+type IngestURLsRequest struct {
+	URLs []string `json:"urls"`
+}
+
+type ChatRequest struct {
+	SessionID string `json:"session_id"`
+	Message   string `json:"message"`
+}
+
+type ChatResponse struct {
+	SessionID      string              `json:"session_id"`
+	Answer         string              `json:"answer"`
+	RewrittenQuery string              `json:"rewritten_query"`
+	Sources        []SourceAttribution `json:"sources"`
+}
+
 type GraphQLResponse struct {
 	Get struct {
 		Document []struct {
-			Text string `json:"text"`
+			Text       string `json:"text"`
+			Source     string `json:"source"`
+			Title      string `json:"title"`
+			DocID      string `json:"doc_id"`
+			ChunkIndex int    `json:"chunk_index"`
+			Additional struct {
+				Distance float64 `json:"distance"`
+			} `json:"_additional"`
 		} `json:"Document"`
 	} `json:"Get"`
 }
 
+// retrieveDocuments runs the hybrid dense+BM25 retrieval and RRF fusion
+// shared by /ask and /chat.
+func retrieveDocuments(ctx context.Context, embedder Embedder, store VectorStore, question string, alpha float64, rrfK, limit int) ([]RetrievedDoc, error) {
+	vector, err := embedder.Embed(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	dense, err := store.Query(ctx, vector, limit)
+	if err != nil {
+		return nil, err
+	}
+	sparse, err := store.QueryBM25(ctx, question, limit)
+	if err != nil {
+		return nil, err
+	}
+	return reciprocalRankFusion(dense, sparse, alpha, rrfK, limit), nil
+}
+
+// toSources converts fused retrieval results into the citation shape
+// returned to callers.
+func toSources(docs []RetrievedDoc) []SourceAttribution {
+	sources := make([]SourceAttribution, len(docs))
+	for i, doc := range docs {
+		sources[i] = SourceAttribution{
+			DocID:      doc.DocID,
+			Source:     doc.Source,
+			Title:      doc.Title,
+			ChunkIndex: doc.ChunkIndex,
+			Score:      doc.Score,
+			Snippet:    snippetOf(doc.Text, 200),
+		}
+	}
+	return sources
+}
+
+// snippetOf truncates text to at most n runes for display in a citation.
+func snippetOf(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
+
+// needsGeminiClient reports whether any of the selected backends talk to
+// Google's genai SDK, so we only require GEMINI_KEY when it's actually used.
+func needsGeminiClient() bool {
+	return llmBackend == "" || llmBackend == "gemini" || embedBackend == "" || embedBackend == "gemini"
+}
+
+// queryFloat reads a float64 query param, falling back to def if it's
+// absent or malformed.
+func queryFloat(c *gin.Context, key string, def float64) float64 {
+	v, err := strconv.ParseFloat(c.Query(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// queryInt reads an int query param, falling back to def if it's absent or
+// malformed.
+func queryInt(c *gin.Context, key string, def int) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
 func main() {
 	fmt.Println(collectionClass)
 	ctx := context.Background()
-	genClient, err := genai.NewClient(ctx, option.WithAPIKey(geminiKey))
+
+	var genClient *genai.Client
+	if needsGeminiClient() {
+		var err error
+		genClient, err = genai.NewClient(ctx, option.WithAPIKey(geminiKey))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	store, err := newVectorStore(vectorBackend)
 	if err != nil {
 		log.Fatal(err)
-
 	}
-	client, err := weaviate.NewClient(weaviate.Config{
-		Host:   "localhost:5555",
-		Scheme: "http",
-	})
+	embedder, err := newEmbedder(ctx, embedBackend, genClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	llm, err := newLLM(ctx, llmBackend, genClient)
 	if err != nil {
 		log.Fatal(err)
 	}
-	embeddingModel := genClient.EmbeddingModel(embeddingModelName)
-	generativeModel := genClient.GenerativeModel(llmModel)
+
+	var chatModel *genai.GenerativeModel
+	if genClient != nil && (llmBackend == "" || llmBackend == "gemini") {
+		chatModel = genClient.GenerativeModel(llmModel)
+	}
+	chatStore := newChatStore(chatSessionTTL)
+
+	ingestStore := newIngestStore()
+	ingestPool := newIngestPool(ingestWorkerCount)
 
 	gin.SetMode(gin.DebugMode)
 	router := gin.New()
@@ -79,39 +216,52 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		batch := embeddingModel.NewBatch()
-		for _, v := range req.Documents {
-			batch.AddContent(genai.Text(v))
+
+		var chunks []string
+		var props []map[string]any
+		for _, doc := range req.Documents {
+			docID := doc.ID
+			if docID == "" {
+				docID = uuid.NewString()
+			}
+			for i, chunk := range chunkText(doc.Text, chunkSize, chunkOverlap) {
+				p := map[string]any{
+					"text":        chunk,
+					"source":      doc.Source,
+					"title":       doc.Title,
+					"doc_id":      docID,
+					"chunk_index": i,
+				}
+				for k, v := range doc.Metadata {
+					p[k] = v
+				}
+				chunks = append(chunks, chunk)
+				props = append(props, p)
+			}
 		}
-		embedModelResp, err := embeddingModel.BatchEmbedContents(c, batch)
+
+		embeddings, err := embedder.BatchEmbed(c, chunks)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		log.Println("Embeddings generated successfully")
-		if len(embedModelResp.Embeddings) != len(req.Documents) {
-			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("expected %d embeddings, got %d", len(req.Documents), len(embedModelResp.Embeddings))})
-			return
-		}
-		vectorObjs := make([]*models.Object, len(req.Documents))
 
-		for i, doc := range req.Documents {
-			vectorObjs[i] = &models.Object{
-				Class: collectionClass,
-				Properties: map[string]any{
-					"text": doc,
-				},
-				Vector: embedModelResp.Embeddings[i].Values,
+		vectorObjs := make([]VectorObject, len(chunks))
+		for i, p := range props {
+			vectorObjs[i] = VectorObject{
+				Vector:     embeddings[i],
+				Properties: p,
 			}
 		}
 
-		_, err = client.Batch().ObjectsBatcher().WithObjects(vectorObjs...).Do(ctx)
-		if err != nil {
+		if err := store.Upsert(ctx, vectorObjs); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Successfully generated documents",
+			"chunks":  len(chunks),
 		})
 	})
 	router.POST("/ask", func(c *gin.Context) {
@@ -120,54 +270,145 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		embedModelResp, err := embeddingModel.EmbedContent(c, genai.Text(req.Question))
+		alpha := queryFloat(c, "alpha", 0.5)
+		rrfK := queryInt(c, "k", 60)
+		limit := queryInt(c, "limit", 4)
+
+		docs, err := retrieveDocuments(ctx, embedder, store, req.Question, alpha, rrfK, limit)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		grahpQ := client.GraphQL()
-		result, err := grahpQ.Get().
-			WithNearVector(grahpQ.NearVectorArgBuilder().WithVector(embedModelResp.Embedding.Values)).
-			WithClassName(collectionClass).
-			WithFields(graphql.Field{Name: "text"}).
-			WithLimit(4).
-			Do(ctx)
+		var out []string
+		for _, doc := range docs {
+			out = append(out, doc.Text)
+		}
+		sources := toSources(docs)
+
+		ragQuery := fmt.Sprintf(template, req.Question, strings.Join(out, "\n"))
+
+		if c.Query("stream") == "true" {
+			tokens, errs := llm.GenerateStream(c.Request.Context(), ragQuery)
+			c.Stream(func(w io.Writer) bool {
+				for t := range tokens {
+					c.SSEvent("message", t)
+					return true
+				}
+				if err := <-errs; err != nil {
+					c.SSEvent("error", err.Error())
+					return false
+				}
+				c.SSEvent("sources", sources)
+				return false
+			})
+			return
+		}
+
+		answer, err := llm.Generate(ctx, ragQuery)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		byteData, err := json.Marshal(result.Data)
+		c.JSON(http.StatusOK, AskResponse{Answer: answer, Sources: sources})
+	})
+	router.POST("/chat", func(c *gin.Context) {
+		if chatModel == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "/chat requires LLM_BACKEND=gemini"})
+			return
+		}
+		var req ChatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		alpha := queryFloat(c, "alpha", 0.5)
+		rrfK := queryInt(c, "k", 60)
+		limit := queryInt(c, "limit", 4)
+
+		sessionID, session := chatStore.getOrCreate(req.SessionID, chatModel)
+		session.Lock()
+		defer session.Unlock()
+
+		rewrittenQuery, err := condenseQuestion(ctx, llm, session.chat, req.Message)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		var resp GraphQLResponse
-		err = json.Unmarshal(byteData, &resp)
+
+		docs, err := retrieveDocuments(ctx, embedder, store, rewrittenQuery, alpha, rrfK, limit)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		var out []string
-		for _, doc := range resp.Get.Document {
+		for _, doc := range docs {
 			out = append(out, doc.Text)
 		}
+		ragQuery := fmt.Sprintf(template, rewrittenQuery, strings.Join(out, "\n"))
 
-		ragQuery := fmt.Sprintf(template, req.Question, strings.Join(out, "\n"))
-		llmResp, err := generativeModel.GenerateContent(ctx, genai.Text(ragQuery))
+		resp, err := session.chat.SendMessage(ctx, genai.Text(ragQuery))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		var respContents []string
-		for _, part := range llmResp.Candidates[0].Content.Parts {
-			if pt, ok := part.(genai.Text); ok {
-				respContents = append(respContents, string(pt))
-			} else {
-				log.Printf("bad type of part: %v", pt)
-				c.JSON(http.StatusBadRequest, fmt.Errorf("unexpected content part type %T", pt))
+		answer, err := joinTextParts(resp)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		replaceLastUserTurn(session.chat, req.Message)
+		trimHistory(session.chat, chatMaxTurns)
+
+		c.JSON(http.StatusOK, ChatResponse{
+			SessionID:      sessionID,
+			Answer:         answer,
+			RewrittenQuery: rewrittenQuery,
+			Sources:        toSources(docs),
+		})
+	})
+
+	router.POST("/ingest", func(c *gin.Context) {
+		var sources []ingestSource
+
+		if form, err := c.MultipartForm(); err == nil && len(form.File["files"]) > 0 {
+			sources, err = sourcesFromMultipart(form.File["files"])
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else {
+			var req IngestURLsRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
+			sources = sourcesFromURLs(req.URLs)
+		}
+
+		if len(sources) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no files or urls provided"})
+			return
+		}
+
+		job := ingestStore.create(len(sources))
+		for _, src := range sources {
+			src := src
+			ingestPool.submit(func() {
+				job.markStarted()
+				err := processSource(ctx, src, embedder, store)
+				job.recordResult(err)
+			})
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID})
+	})
+	router.GET("/ingest/:job_id", func(c *gin.Context) {
+		job, ok := ingestStore.get(c.Param("job_id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return
 		}
-		c.JSON(http.StatusOK, strings.Join(respContents, "\n"))
+		c.JSON(http.StatusOK, job.snapshot())
 	})
 
 	if err := router.Run(":8080"); err != nil {
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+var (
+	ollamaHost       = envOrDefault("OLLAMA_HOST", "http://localhost:11434")
+	ollamaModel      = envOrDefault("OLLAMA_MODEL", "llama3")
+	ollamaEmbedModel = envOrDefault("OLLAMA_EMBED_MODEL", "nomic-embed-text")
+)
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// ollamaClient talks to a local Ollama daemon and implements both Embedder
+// and LLM, so a fully local backend needs no Google API key.
+type ollamaClient struct {
+	host       string
+	httpClient *http.Client
+}
+
+func newOllamaClient() *ollamaClient {
+	return &ollamaClient{host: ollamaHost, httpClient: http.DefaultClient}
+}
+
+type ollamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+type ollamaEmbeddingResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+func (o *ollamaClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(ollamaEmbeddingRequest{Model: ollamaEmbedModel, Prompt: text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.host+"/api/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama embeddings: unexpected status %s", resp.Status)
+	}
+	var out ollamaEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.Embedding, nil
+}
+
+func (o *ollamaClient) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		vec, err := o.Embed(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+type ollamaGenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+type ollamaGenerateChunk struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+}
+
+func (o *ollamaClient) Generate(ctx context.Context, prompt string) (string, error) {
+	tokens, errs := o.generate(ctx, prompt, false)
+	var out bytes.Buffer
+	for t := range tokens {
+		out.WriteString(t)
+	}
+	if err := <-errs; err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (o *ollamaClient) GenerateStream(ctx context.Context, prompt string) (<-chan string, <-chan error) {
+	return o.generate(ctx, prompt, true)
+}
+
+// generate issues a request against Ollama's /api/generate endpoint and
+// streams the NDJSON response body, one `response` fragment per line,
+// mirroring the `stream` helper in Ollama's own Go client.
+func (o *ollamaClient) generate(ctx context.Context, prompt string, stream bool) (<-chan string, <-chan error) {
+	tokens := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(tokens)
+		defer close(errs)
+		body, err := json.Marshal(ollamaGenerateRequest{Model: ollamaModel, Prompt: prompt, Stream: stream})
+		if err != nil {
+			errs <- err
+			return
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.host+"/api/generate", bytes.NewReader(body))
+		if err != nil {
+			errs <- err
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := o.httpClient.Do(req)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			errs <- fmt.Errorf("ollama generate: unexpected status %s", resp.Status)
+			return
+		}
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var chunk ollamaGenerateChunk
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				errs <- err
+				return
+			}
+			if chunk.Response != "" {
+				tokens <- chunk.Response
+			}
+			if chunk.Done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- err
+		}
+	}()
+	return tokens, errs
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// documentFields are the properties fetched for every retrieval query, used
+// to attribute an answer back to its source chunk.
+var documentFields = []graphql.Field{
+	{Name: "text"},
+	{Name: "source"},
+	{Name: "title"},
+	{Name: "doc_id"},
+	{Name: "chunk_index"},
+	{Name: "_additional", Fields: []graphql.Field{{Name: "distance"}}},
+}
+
+// weaviateStore is the VectorStore implementation backed by a Weaviate
+// instance, hardcoded to localhost:5555 to match the rest of this package.
+type weaviateStore struct {
+	client *weaviate.Client
+}
+
+func newWeaviateStore() (*weaviateStore, error) {
+	client, err := weaviate.NewClient(weaviate.Config{
+		Host:   "localhost:5555",
+		Scheme: "http",
+	})
+	if err != nil {
+		return nil, err
+	}
+	store := &weaviateStore{client: client}
+	if err := store.ensureSchema(context.Background()); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// ensureSchema creates the collectionClass in Weaviate if it doesn't exist
+// yet, with the properties the chunking and attribution pipeline relies on.
+func (s *weaviateStore) ensureSchema(ctx context.Context) error {
+	schema, err := s.client.Schema().Getter().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, class := range schema.Classes {
+		if class.Class == collectionClass {
+			return nil
+		}
+	}
+	class := &models.Class{
+		Class: collectionClass,
+		Properties: []*models.Property{
+			{Name: "text", DataType: []string{"text"}},
+			{Name: "source", DataType: []string{"text"}},
+			{Name: "title", DataType: []string{"text"}},
+			{Name: "doc_id", DataType: []string{"text"}},
+			{Name: "chunk_index", DataType: []string{"int"}},
+		},
+	}
+	return s.client.Schema().ClassCreator().WithClass(class).Do(ctx)
+}
+
+func (s *weaviateStore) Upsert(ctx context.Context, objs []VectorObject) error {
+	vectorObjs := make([]*models.Object, len(objs))
+	for i, obj := range objs {
+		vectorObjs[i] = &models.Object{
+			Class:      collectionClass,
+			Properties: obj.Properties,
+			Vector:     obj.Vector,
+		}
+	}
+	_, err := s.client.Batch().ObjectsBatcher().WithObjects(vectorObjs...).Do(ctx)
+	return err
+}
+
+func (s *weaviateStore) Query(ctx context.Context, vector []float32, limit int) ([]RetrievedDoc, error) {
+	grahpQ := s.client.GraphQL()
+	result, err := grahpQ.Get().
+		WithNearVector(grahpQ.NearVectorArgBuilder().WithVector(vector)).
+		WithClassName(collectionClass).
+		WithFields(documentFields...).
+		WithLimit(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocuments(result.Data)
+}
+
+func (s *weaviateStore) QueryBM25(ctx context.Context, query string, limit int) ([]RetrievedDoc, error) {
+	grahpQ := s.client.GraphQL()
+	result, err := grahpQ.Get().
+		WithBM25(grahpQ.BM25ArgBuilder().WithQuery(query)).
+		WithClassName(collectionClass).
+		WithFields(documentFields...).
+		WithLimit(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocuments(result.Data)
+}
+
+// parseDocuments decodes a GraphQL Get response's raw data into the
+// RetrievedDoc slice shared by dense and BM25 queries alike.
+func parseDocuments(data any) ([]RetrievedDoc, error) {
+	byteData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var resp GraphQLResponse
+	if err := json.Unmarshal(byteData, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]RetrievedDoc, len(resp.Get.Document))
+	for i, doc := range resp.Get.Document {
+		out[i] = RetrievedDoc{
+			DocID:      doc.DocID,
+			Source:     doc.Source,
+			Title:      doc.Title,
+			ChunkIndex: doc.ChunkIndex,
+			Text:       doc.Text,
+			Score:      doc.Additional.Distance,
+		}
+	}
+	return out, nil
+}